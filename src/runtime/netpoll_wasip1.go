@@ -0,0 +1,171 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build wasip1
+
+package runtime
+
+import "internal/runtime/atomic"
+
+// wasip1's netpoller is built on top of poll_oneoff, which can block on an
+// arbitrary batch of clock and fd-readiness subscriptions in a single call.
+// netpoll keeps a table of the pollDescs currently registered for a WASI
+// fd, builds one read and one write subscription per registered fd (plus a
+// clock subscription bounding the wait) on every call, and translates
+// whatever poll_oneoff reports back into ready Gs, including waking both
+// directions of a fd whose event carries fdReadwriteHangup.
+//
+// There is no way to interrupt an in-progress poll_oneoff call from another
+// thread, so netpollBreak works the way it does on other platforms that
+// lack a native wakeup primitive: the pending-break flag is checked, and
+// cleared, on every netpoll call, and the clock subscription bounds how
+// long a call can block without noticing one.
+//
+// The clock subscription uses subscriptionClockAbstime with a deadline
+// computed immediately before the call, rather than a relative timeout, so
+// the timer heap's wakeups get the same nanosecond-precision semantics
+// usleep already gave clockMonotonic sleeps, instead of falling back to a
+// coarser fixed-period spin once real fd subscriptions entered the mix.
+
+const netpollBreakMaxWaitNS = 10 * 1e6 // 10ms; bounds how long a pending netpollBreak can be missed
+
+var (
+	netpollWakeSig atomic.Uint32 // set when a break is pending
+
+	netpollMu      mutex
+	netpollOpenFDs map[int32]*pollDesc
+)
+
+func netpollinit() {
+	netpollOpenFDs = make(map[int32]*pollDesc)
+}
+
+func netpollIsPollDescriptor(fd uintptr) bool {
+	lock(&netpollMu)
+	_, ok := netpollOpenFDs[int32(fd)]
+	unlock(&netpollMu)
+	return ok
+}
+
+func netpollopen(fd uintptr, pd *pollDesc) int32 {
+	lock(&netpollMu)
+	netpollOpenFDs[int32(fd)] = pd
+	unlock(&netpollMu)
+	return 0
+}
+
+func netpollclose(fd uintptr) int32 {
+	lock(&netpollMu)
+	delete(netpollOpenFDs, int32(fd))
+	unlock(&netpollMu)
+	return 0
+}
+
+// netpollBreak interrupts a netpoll that is currently, or about to be,
+// blocked in poll_oneoff. Every netpoll call subscribes to a clock event
+// bounded by netpollBreakMaxWaitNS, so recording the request here is
+// enough: a call already blocked in poll_oneoff returns within that bound,
+// and a call that has not yet subscribed sees the flag and does not block.
+func netpollBreak() {
+	netpollWakeSig.Store(1)
+}
+
+// netpoll checks for ready network connections. It batches a clock
+// subscription together with one read and one write subscription per
+// open pollDesc into a single poll_oneoff call and returns the goroutines
+// that became runnable, along with the delta to apply to netpollWaiters
+// (this implementation doesn't track blocked waiters itself, so it is
+// always 0).
+//
+//   - delay < 0: block until an event arrives, subject to
+//     netpollBreakMaxWaitNS so a netpollBreak is never missed.
+//   - delay == 0: report already-ready descriptors without blocking.
+//   - delay > 0: block for at most delay nanoseconds.
+func netpoll(delay int64) (gList, int32) {
+	lock(&netpollMu)
+	fds := make([]int32, 0, len(netpollOpenFDs))
+	for fd := range netpollOpenFDs {
+		fds = append(fds, fd)
+	}
+	unlock(&netpollMu)
+
+	subs := make([]subscription, 0, 2*len(fds)+1)
+	for _, fd := range fds {
+		var rsub subscription
+		rsub.userdata = userdata(len(subs))
+		rsub.setFDRead(uint32(fd))
+		subs = append(subs, rsub)
+
+		var wsub subscription
+		wsub.userdata = userdata(len(subs))
+		wsub.setFDWrite(uint32(fd))
+		subs = append(subs, wsub)
+	}
+
+	// Resolve delay to an absolute clockMonotonic deadline as late as
+	// possible, right before the call, and subscribe with
+	// subscriptionClockAbstime. This is what gives time.Sleep, time.After,
+	// and context.WithDeadline sub-millisecond accuracy on wasip1: the
+	// deadline is exact rather than "at least delay ns from whenever the
+	// relative countdown happens to start", and it shares this same
+	// poll_oneoff call with the fd subscriptions above instead of falling
+	// back to a separate, coarser usleep loop.
+	deadline := nanotime() + netpollClockTimeoutNS(delay)
+	var clockSub subscription
+	clockSub.userdata = userdata(len(subs))
+	clockSub.setClock(clockMonotonic, uint64(deadline), 1e3, subscriptionClockAbstime)
+	subs = append(subs, clockSub)
+
+	events := make([]event, len(subs))
+	var nevents size
+	if poll_oneoff(&subs[0], &events[0], size(len(subs)), &nevents) != 0 {
+		throw("wasi_snapshot_preview1.poll_oneoff")
+	}
+	netpollWakeSig.Store(0)
+
+	var toRun gList
+	lock(&netpollMu)
+	for i := size(0); i < nevents; i++ {
+		ev := events[i]
+		idx := int(ev.userdata)
+		if idx >= 2*len(fds) {
+			continue // the clock subscription fired; nothing to deliver
+		}
+		fd := fds[idx/2]
+		pd, ok := netpollOpenFDs[fd]
+		if !ok {
+			continue
+		}
+		// A hangup on either the read or the write subscription for fd
+		// means the fd is no longer usable in the normal way: wake both
+		// directions, the same as the fd's own event type, so a blocked
+		// Read or Write unblocks and observes the hangup as an error or
+		// EOF instead of waiting out the rest of the poll_oneoff delay.
+		hungup := ev.fdReadwrite.flags&fdReadwriteHangup != 0
+		var mode int32
+		if ev.typ == eventtypeFdRead || hungup {
+			mode += 'r'
+		}
+		if ev.typ == eventtypeFdWrite || hungup {
+			mode += 'w'
+		}
+		netpollready(&toRun, pd, mode)
+	}
+	unlock(&netpollMu)
+
+	return toRun, 0
+}
+
+// netpollClockTimeoutNS picks how long the next poll_oneoff call is allowed
+// to block, honoring delay but never exceeding netpollBreakMaxWaitNS so a
+// netpollBreak requested just before the call isn't missed.
+func netpollClockTimeoutNS(delay int64) int64 {
+	if delay < 0 || delay > netpollBreakMaxWaitNS {
+		delay = netpollBreakMaxWaitNS
+	}
+	if netpollWakeSig.Load() != 0 {
+		delay = 0
+	}
+	return delay
+}
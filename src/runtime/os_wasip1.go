@@ -290,96 +290,23 @@ func nanotime1() int64 {
 	return int64(*timePtr)
 }
 
-// This is a bit of a hack because wasi expects 8 byte aligned pointers
-// when the return value is a uint64, but the wasm32 port doesn't adher
-// to managing the stack at 8 byte alignment. So because we don't have
-// real threads anyway, we effectively use a set of globals as 8 byte
-// aligned pointers, knowing that (at present) there is no chance that
-// another goroutine can be running and reuse the same pointer. Note that
-// the pointers are grab, passed to WASI, then read in the same function
-// always.
-type tmpStackS struct {
-	_ [1024]byte
-}
-
-var tmpStack tmpStackS
-
+// tmpUint64_1 and tmpUint64_2 return this M's 8-byte-aligned scratch words.
+// wasi expects 8 byte aligned pointers when the return value is a uint64,
+// but the wasm32 port doesn't adhere to managing the stack at 8 byte
+// alignment, so walltime1, nanotime1, and future WASI calls needing an
+// aligned out-pointer borrow a pair of words allocated once per M at M
+// bring-up. The fields themselves live on the shared mOS (see os_wasm.go,
+// built for all wasm GOOS targets); wasip1 is just the only one that uses
+// them. Because the slots live on the M rather than in package-level
+// globals, two Gs running on different Ms can both be inside a WASI call
+// without one clobbering the other's pointer, which matters once wasip1
+// grows threads or async I/O.
 func tmpUint64_1() *uint64 {
-	ptr := uintptr(unsafe.Pointer(&tmpStack))
-
-	if ptr%8 != 0 {
-		ptr += 8 - (ptr % 8)
-	}
-
-	return (*uint64)(unsafe.Pointer(ptr))
+	return getg().m.wasip1TmpUint64_1
 }
 
 func tmpUint64_2() *uint64 {
-	ptr := uintptr(unsafe.Pointer(&tmpStack))
-
-	ptr += 16
-
-	if ptr%8 != 0 {
-		ptr += 8 - (ptr % 8)
-	}
-
-	return (*uint64)(unsafe.Pointer(ptr))
-}
-
-// This is a weird one. We use generics to allocate various configurations
-// of T to find one that is allocated on an 8 byte boundary. This is wasteful
-// but it allows the GC to track the returned value correctly so we don't have
-// to use a pool of already aligned values.
-func NewAligned[T any](t **T) {
-	v0 := new(T)
-	if uintptr(unsafe.Pointer(v0))%8 == 0 {
-		*t = v0
-		return
-	}
-
-	v2 := new(struct {
-		_ [2]byte
-		t T
-	})
-	if uintptr(unsafe.Pointer(&v2.t))%8 == 0 {
-		*t = &v2.t
-		return
-	}
-
-	v4 := new(struct {
-		_ [4]byte
-		t T
-	})
-	if uintptr(unsafe.Pointer(&v4.t))%8 == 0 {
-		*t = &v4.t
-		return
-	}
-
-	v6 := new(struct {
-		_ [6]byte
-		t T
-	})
-	if uintptr(unsafe.Pointer(&v6.t))%8 == 0 {
-		*t = &v6.t
-		return
-	}
-
-	v8 := new(struct {
-		_ [8]byte
-		t T
-	})
-	if uintptr(unsafe.Pointer(&v8.t))%8 == 0 {
-		*t = &v8.t
-		return
-	}
-
-	println("0ptr= ", uintptr(unsafe.Pointer(v0)))
-	println("2ptr= ", uintptr(unsafe.Pointer(&v2.t)))
-	println("4ptr= ", uintptr(unsafe.Pointer(&v4.t)))
-	println("6ptr= ", uintptr(unsafe.Pointer(&v6.t)))
-	println("8ptr= ", uintptr(unsafe.Pointer(&v8.t)))
-
-	throw("failed to allocate aligned value")
+	return getg().m.wasip1TmpUint64_2
 }
 
 type littleEndian struct{}
@@ -0,0 +1,198 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build wasip2
+
+package runtime
+
+import (
+	"structs"
+	"unsafe"
+)
+
+// This file targets the WASI preview2 / component model ABI, as opposed to
+// os_wasip1.go which targets the preview1 ("snapshot") ABI. The two are
+// selected by GOOS=wasip1 vs GOOS=wasip2 respectively and are mutually
+// exclusive build targets, but they expose the same higher-level Go API
+// (write1, usleep, readRandom, goenvs, walltime1, nanotime1) so that the
+// rest of the runtime does not need to know which ABI it is running on.
+//
+// The component model passes resources as own/borrow handles (plain
+// uint32 indices into a per-instance table) rather than linear-memory
+// pointers, and represents variable-length data such as list<u8> as a
+// (pointer, length) pair in linear memory that the host reads or fills in
+// place, much like the preview1 iovec convention.
+
+// own and borrow are the two component-model handle flavors. An own handle
+// transfers ownership of the underlying resource to whoever receives it
+// and must eventually be dropped; a borrow handle is valid only for the
+// duration of the call that received it.
+type own = uint32
+type borrow = uint32
+
+// list is the in-memory shape of a component-model list<u8>: a pointer to
+// the first element and a length, both passed by value the same way a Go
+// slice header's first two words would be.
+type list struct {
+	_   structs.HostLayout
+	ptr uintptr32
+	len size
+}
+
+func newList(p unsafe.Pointer, n int) list {
+	return list{ptr: uintptr32(uintptr(p)), len: size(n)}
+}
+
+// wasi:io/poll@0.2.0
+
+//go:wasmimport wasi:io/poll@0.2.0 [resource-drop]pollable
+//go:noescape
+func pollableDrop(h own)
+
+//go:wasmimport wasi:io/poll@0.2.0 [method]pollable.block
+//go:noescape
+func pollableBlock(h borrow)
+
+// wasi:clocks/monotonic-clock@0.2.0
+
+//go:wasmimport wasi:clocks/monotonic-clock@0.2.0 now
+//go:noescape
+func monotonicClockNow() uint64
+
+//go:wasmimport wasi:clocks/monotonic-clock@0.2.0 subscribe-duration
+//go:noescape
+func monotonicClockSubscribeDuration(d uint64) own
+
+// wasi:clocks/wall-clock@0.2.0
+
+type wallClockDatetime struct {
+	_           structs.HostLayout
+	seconds     uint64
+	nanoseconds uint32
+}
+
+// go:wasmimport only lowers a single scalar (or void) result across the
+// import boundary, so calls that conceptually return a multi-field record
+// (wallClockDatetime, list) instead take an out-pointer that the host
+// fills in place, the same convention the preview1 shim uses for event and
+// the clock_time_get timestamp in os_wasip1.go.
+
+//go:wasmimport wasi:clocks/wall-clock@0.2.0 now
+//go:noescape
+func wallClockNow(out *wallClockDatetime)
+
+// wasi:random/random@0.2.0
+
+//go:wasmimport wasi:random/random@0.2.0 get-random-bytes
+//go:noescape
+func randomGetRandomBytes(n uint64, out *list)
+
+// wasi:cli/environment@0.2.0
+
+//go:wasmimport wasi:cli/environment@0.2.0 get-arguments
+//go:noescape
+func environmentGetArguments(out *list)
+
+//go:wasmimport wasi:cli/environment@0.2.0 get-environment
+//go:noescape
+func environmentGetEnvironment(out *list)
+
+// wasi:cli/stdout@0.2.0, wasi:cli/stderr@0.2.0, and wasi:io/streams@0.2.0
+
+//go:wasmimport wasi:cli/stdout@0.2.0 get-stdout
+//go:noescape
+func cliGetStdout() own
+
+//go:wasmimport wasi:cli/stderr@0.2.0 get-stderr
+//go:noescape
+func cliGetStderr() own
+
+//go:wasmimport wasi:io/streams@0.2.0 [resource-drop]output-stream
+//go:noescape
+func outputStreamDrop(h own)
+
+//go:wasmimport wasi:io/streams@0.2.0 [method]output-stream.blocking-write-and-flush
+//go:noescape
+func outputStreamBlockingWriteAndFlush(h borrow, buf list) uint64
+
+//go:wasmimport wasi:cli/exit@0.2.0 exit
+func cliExit(status int32)
+
+func exit(code int32) {
+	cliExit(code)
+}
+
+func write1(fd uintptr, p unsafe.Pointer, n int32) int32 {
+	// fd 1/2 (stdout/stderr) are the only descriptors the runtime itself
+	// ever writes to directly, for things like panics and fatal throws;
+	// everything else goes through os.File, which has its own preview2
+	// plumbing. The fd argument must be honored here, not assumed to
+	// always mean stdout, or stderr output silently ends up on stdout.
+	var stream own
+	switch fd {
+	case 1:
+		stream = cliGetStdout()
+	case 2:
+		stream = cliGetStderr()
+	default:
+		throw("write1: unsupported fd")
+	}
+	defer outputStreamDrop(stream)
+	status := outputStreamBlockingWriteAndFlush(stream, newList(p, int(n)))
+	if status != 0 {
+		throw("wasi:io/streams.output-stream.blocking-write-and-flush failed")
+	}
+	return n
+}
+
+func usleep(usec uint32) {
+	h := monotonicClockSubscribeDuration(uint64(usec) * 1e3)
+	pollableBlock(h)
+	pollableDrop(h)
+}
+
+func readRandom(r []byte) int {
+	if len(r) == 0 {
+		return 0
+	}
+	var got list
+	randomGetRandomBytes(uint64(len(r)), &got)
+	n := copy(r, unsafe.Slice((*byte)(unsafe.Pointer(uintptr(got.ptr))), int(got.len)))
+	return n
+}
+
+func splitNulSeparated(l list) []string {
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(l.ptr))), int(l.len))
+	var out []string
+	start := 0
+	for i, b := range buf {
+		if b == 0 {
+			out = append(out, string(buf[start:i]))
+			start = i + 1
+		}
+	}
+	return out
+}
+
+func goenvs() {
+	var args, env list
+	environmentGetArguments(&args)
+	environmentGetEnvironment(&env)
+	argslice = splitNulSeparated(args)
+	envs = splitNulSeparated(env)
+}
+
+func walltime() (sec int64, nsec int32) {
+	return walltime1()
+}
+
+func walltime1() (sec int64, nsec int32) {
+	var t wallClockDatetime
+	wallClockNow(&t)
+	return int64(t.seconds), int32(t.nanoseconds)
+}
+
+func nanotime1() int64 {
+	return int64(monotonicClockNow())
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build wasm
+
+package runtime
+
+import "unsafe"
+
+// mOS holds per-M state for GOARCH=wasm. It is shared by every wasm GOOS
+// target (js, wasip1, wasip2), which is why it lives here under a
+// wasm-GOARCH build tag rather than in one of the GOOS-specific os_*.go
+// files: those files' build tags (wasip1, wasip2, ...) are all satisfied
+// alongside this one, so declaring mOS there too would be a duplicate
+// declaration.
+type mOS struct {
+	// wasip1TmpUint64_1 and wasip1TmpUint64_2 are 8-byte-aligned scratch
+	// words used by the wasip1 WASI shim (see os_wasip1.go) for WASI calls
+	// that return a uint64 through an out-pointer. They are allocated for
+	// every M regardless of GOOS by minit, but unused outside wasip1.
+	wasip1TmpUint64_1 *uint64
+	wasip1TmpUint64_2 *uint64
+}
+
+func minit() {
+	NewAligned(&getg().m.wasip1TmpUint64_1)
+	NewAligned(&getg().m.wasip1TmpUint64_2)
+}
+
+func unminit() {
+}
+
+func mdestroy(mp *m) {
+}
+
+// This is a weird one. We use generics to allocate various configurations
+// of T to find one that is allocated on an 8 byte boundary. This is wasteful
+// but it allows the GC to track the returned value correctly so we don't have
+// to use a pool of already aligned values.
+func NewAligned[T any](t **T) {
+	v0 := new(T)
+	if uintptr(unsafe.Pointer(v0))%8 == 0 {
+		*t = v0
+		return
+	}
+
+	v2 := new(struct {
+		_ [2]byte
+		t T
+	})
+	if uintptr(unsafe.Pointer(&v2.t))%8 == 0 {
+		*t = &v2.t
+		return
+	}
+
+	v4 := new(struct {
+		_ [4]byte
+		t T
+	})
+	if uintptr(unsafe.Pointer(&v4.t))%8 == 0 {
+		*t = &v4.t
+		return
+	}
+
+	v6 := new(struct {
+		_ [6]byte
+		t T
+	})
+	if uintptr(unsafe.Pointer(&v6.t))%8 == 0 {
+		*t = &v6.t
+		return
+	}
+
+	v8 := new(struct {
+		_ [8]byte
+		t T
+	})
+	if uintptr(unsafe.Pointer(&v8.t))%8 == 0 {
+		*t = &v8.t
+		return
+	}
+
+	println("0ptr= ", uintptr(unsafe.Pointer(v0)))
+	println("2ptr= ", uintptr(unsafe.Pointer(&v2.t)))
+	println("4ptr= ", uintptr(unsafe.Pointer(&v4.t)))
+	println("6ptr= ", uintptr(unsafe.Pointer(&v6.t)))
+	println("8ptr= ", uintptr(unsafe.Pointer(&v8.t)))
+
+	throw("failed to allocate aligned value")
+}
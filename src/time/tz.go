@@ -1,21 +1,115 @@
-package time
-
-import (
-	"errors"
-)
-
-func tzData(name string) ([]byte, bool) {
-	data, ok := files["zoneinfo/"+name]
-	return data, ok
-}
-
-// Address https://github.com/golang/go/issues/21881.
-func loadLocationEmbeddedFile(name string) (*Location, error) {
-	if name == "" || name == "UTC" || name == "Local" {
-		return LoadLocation(name)
-	}
-	if tzdata, ok := tzData(name); ok {
-		return LoadLocationFromTZData(name, tzdata)
-	}
-	return nil, errors.New("unknown location " + name)
-}
+package time
+
+import (
+	"errors"
+	"sync"
+)
+
+// A TZDataFunc supplies encoded IANA time zone data for the zone with the
+// given name, for use by LoadLocation. It reports whether it has data for
+// the name.
+type TZDataFunc func(name string) (data []byte, ok bool)
+
+var (
+	tzProvidersMu sync.RWMutex
+	tzProviders   []TZDataFunc
+)
+
+// RegisterTZDataProvider registers a function to be consulted for time zone
+// data before the embedded copy of the time zone database. Providers are
+// tried in the order they were registered, and the first to report ok
+// supplies the data.
+//
+// Providers are consulted only by loadLocationEmbeddedFile, ahead of the
+// embedded database. The search of the OS zoneinfo directories does not
+// consult them, so a provider does not take precedence over zone data the
+// OS copy already has.
+//
+// RegisterTZDataProvider is intended for programs that want to serve their
+// own copy of the IANA time zone database, for example one fetched at
+// startup or stored alongside the binary, without recompiling the program
+// each time the database changes.
+func RegisterTZDataProvider(provider func(name string) (data []byte, ok bool)) {
+	tzProvidersMu.Lock()
+	tzProviders = append(tzProviders, provider)
+	tzProvidersMu.Unlock()
+}
+
+// tzDataFromProviders returns time zone data for name from the registered
+// providers, trying them in registration order. It is consulted by tzData
+// before the embedded database; the search of the OS zoneinfo directories
+// does not consult it.
+func tzDataFromProviders(name string) ([]byte, bool) {
+	tzProvidersMu.RLock()
+	defer tzProvidersMu.RUnlock()
+	for _, provider := range tzProviders {
+		if data, ok := provider(name); ok {
+			return data, ok
+		}
+	}
+	return nil, false
+}
+
+// loadedZones caches the *Location values returned by loadLocationEmbeddedFile,
+// keyed by name, so that repeated lookups of the same zone don't re-parse the
+// underlying tzdata on every call. ReloadTZData clears it.
+var (
+	loadedZonesMu sync.Mutex
+	loadedZones   map[string]*Location
+)
+
+// ReloadTZData discards any *Location values cached by previous calls to
+// loadLocationEmbeddedFile, so that later calls re-consult the registered
+// providers and the embedded time zone database. It is meant
+// for long-running programs that want to pick up a newer IANA release, or
+// newly registered providers, without restarting.
+func ReloadTZData() {
+	loadedZonesMu.Lock()
+	loadedZones = nil
+	loadedZonesMu.Unlock()
+}
+
+// tzData returns the time zone data for name, consulting the registered
+// providers before falling back to the database embedded in the binary.
+// loadLocationEmbeddedFile is this package's only entry point in front of
+// the embedded database, so it must go through tzData rather than the
+// embedded table directly, or RegisterTZDataProvider has no effect.
+func tzData(name string) ([]byte, bool) {
+	if data, ok := tzDataFromProviders(name); ok {
+		return data, ok
+	}
+	data, ok := files["zoneinfo/"+name]
+	return data, ok
+}
+
+// Address https://github.com/golang/go/issues/21881.
+func loadLocationEmbeddedFile(name string) (*Location, error) {
+	if name == "" || name == "UTC" || name == "Local" {
+		return LoadLocation(name)
+	}
+
+	loadedZonesMu.Lock()
+	if loc, ok := loadedZones[name]; ok {
+		loadedZonesMu.Unlock()
+		return loc, nil
+	}
+	loadedZonesMu.Unlock()
+
+	tzdata, ok := tzData(name)
+	if !ok {
+		return nil, errors.New("unknown location " + name)
+	}
+	loc, err := LoadLocationFromTZData(name, tzdata)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedZonesMu.Lock()
+	if loadedZones == nil {
+		loadedZones = make(map[string]*Location)
+	}
+	loadedZones[name] = loc
+	loadedZonesMu.Unlock()
+
+	return loc, nil
+}
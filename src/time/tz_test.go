@@ -0,0 +1,162 @@
+package time
+
+import (
+	"sync"
+	"testing"
+)
+
+// resetTZState saves and restores the package-level provider and cache
+// state so tests can register fakes without affecting one another.
+func resetTZState(t *testing.T) {
+	t.Helper()
+
+	tzProvidersMu.Lock()
+	savedProviders := tzProviders
+	tzProviders = nil
+	tzProvidersMu.Unlock()
+
+	loadedZonesMu.Lock()
+	savedZones := loadedZones
+	loadedZones = nil
+	loadedZonesMu.Unlock()
+
+	t.Cleanup(func() {
+		tzProvidersMu.Lock()
+		tzProviders = savedProviders
+		tzProvidersMu.Unlock()
+
+		loadedZonesMu.Lock()
+		loadedZones = savedZones
+		loadedZonesMu.Unlock()
+	})
+}
+
+func TestTZDataFromProvidersPreferred(t *testing.T) {
+	resetTZState(t)
+
+	want := []byte("fake-tzdata")
+	RegisterTZDataProvider(func(name string) ([]byte, bool) {
+		if name == "Fake/Zone" {
+			return want, true
+		}
+		return nil, false
+	})
+
+	data, ok := tzDataFromProviders("Fake/Zone")
+	if !ok || string(data) != string(want) {
+		t.Fatalf("tzDataFromProviders(%q) = %q, %v; want %q, true", "Fake/Zone", data, ok, want)
+	}
+}
+
+func TestTZDataFromProvidersOrder(t *testing.T) {
+	resetTZState(t)
+
+	RegisterTZDataProvider(func(name string) ([]byte, bool) { return nil, false })
+	RegisterTZDataProvider(func(name string) ([]byte, bool) { return []byte("second"), true })
+
+	data, ok := tzDataFromProviders("Any/Zone")
+	if !ok || string(data) != "second" {
+		t.Fatalf("tzDataFromProviders(%q) = %q, %v; want %q, true", "Any/Zone", data, ok, "second")
+	}
+}
+
+func TestTZDataFromProvidersNoMatch(t *testing.T) {
+	resetTZState(t)
+
+	RegisterTZDataProvider(func(name string) ([]byte, bool) { return nil, false })
+
+	if _, ok := tzDataFromProviders("Completely/Unknown"); ok {
+		t.Fatalf("tzDataFromProviders reported data for a zone no provider has")
+	}
+}
+
+func TestTzDataPrefersProviderOverEmbedded(t *testing.T) {
+	resetTZState(t)
+
+	const name = "America/New_York"
+	if _, ok := files["zoneinfo/"+name]; !ok {
+		t.Skip("embedded tzdata not available in this build")
+	}
+
+	const want = "not real tzif data, but it should still win"
+	RegisterTZDataProvider(func(n string) ([]byte, bool) {
+		if n == name {
+			return []byte(want), true
+		}
+		return nil, false
+	})
+
+	// tzData is what loadLocationEmbeddedFile actually calls, so this
+	// exercises the wired-up path rather than tzDataFromProviders alone.
+	data, ok := tzData(name)
+	if !ok || string(data) != want {
+		t.Fatalf("tzData(%q) = %q, %v; want %q, true even though the embedded table also has %q", name, data, ok, want, name)
+	}
+}
+
+func TestLoadLocationEmbeddedFileUsesProvider(t *testing.T) {
+	resetTZState(t)
+
+	// Borrow real, valid tzdata for an embedded zone so the registered
+	// provider's data parses successfully, but serve it under a name the
+	// embedded table does not have, so success can only come from the
+	// provider.
+	data, ok := files["zoneinfo/America/New_York"]
+	if !ok {
+		t.Skip("embedded tzdata not available in this build")
+	}
+
+	const fakeName = "Provider/Only"
+	RegisterTZDataProvider(func(n string) ([]byte, bool) {
+		if n == fakeName {
+			return data, true
+		}
+		return nil, false
+	})
+
+	loc, err := loadLocationEmbeddedFile(fakeName)
+	if err != nil {
+		t.Fatalf("loadLocationEmbeddedFile(%q) = _, %v; want success via the registered provider", fakeName, err)
+	}
+	if loc.name != fakeName {
+		t.Fatalf("loadLocationEmbeddedFile(%q).name = %q, want %q", fakeName, loc.name, fakeName)
+	}
+}
+
+func TestReloadTZDataClearsCache(t *testing.T) {
+	resetTZState(t)
+
+	loadedZonesMu.Lock()
+	loadedZones = map[string]*Location{"Fake/Zone": {}}
+	loadedZonesMu.Unlock()
+
+	ReloadTZData()
+
+	loadedZonesMu.Lock()
+	n := len(loadedZones)
+	loadedZonesMu.Unlock()
+	if n != 0 {
+		t.Fatalf("ReloadTZData left %d cached zones, want 0", n)
+	}
+}
+
+func TestRegisterTZDataProviderConcurrent(t *testing.T) {
+	resetTZState(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RegisterTZDataProvider(func(name string) ([]byte, bool) { return nil, false })
+		}()
+	}
+	wg.Wait()
+
+	tzProvidersMu.RLock()
+	n := len(tzProviders)
+	tzProvidersMu.RUnlock()
+	if n != 10 {
+		t.Fatalf("got %d registered providers, want 10", n)
+	}
+}